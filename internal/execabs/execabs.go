@@ -0,0 +1,9 @@
+// License: GPLv3 Copyright: 2022, Kovid Goyal, <kovid at kovidgoyal.net>
+
+// Package execabs answers a single, deceptively platform-specific
+// question: is this file something the shell would run? Callers such as
+// the completion package should never test the unix execute bit directly,
+// since doing so both fails to build on Windows and gives the wrong answer
+// on filesystems, such as FAT or many network mounts, where that bit does
+// not mean what it usually does.
+package execabs
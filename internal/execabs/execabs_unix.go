@@ -0,0 +1,40 @@
+// License: GPLv3 Copyright: 2022, Kovid Goyal, <kovid at kovidgoyal.net>
+
+//go:build unix
+
+package execabs
+
+import (
+	"io/fs"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// IsExecutable reports whether path can be executed, preferring access(2)
+// and only falling back to the file's own mode bits when access(2) itself
+// refuses with EACCES, which some filesystems (FAT mounts, some network
+// mounts) return even for files whose mode bits do mark them executable.
+func IsExecutable(path string, d fs.DirEntry) bool {
+	switch unix.Access(path, unix.X_OK) {
+	case nil:
+		return true
+	case unix.EACCES:
+		return mode_is_executable(path, d)
+	default:
+		return false
+	}
+}
+
+func mode_is_executable(path string, d fs.DirEntry) bool {
+	if d != nil {
+		if info, err := d.Info(); err == nil {
+			return info.Mode()&0o111 != 0
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return info.Mode()&0o111 != 0
+}
@@ -0,0 +1,60 @@
+// License: GPLv3 Copyright: 2022, Kovid Goyal, <kovid at kovidgoyal.net>
+
+//go:build unix
+
+package execabs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsExecutable(t *testing.T) {
+	dir := t.TempDir()
+
+	exe := filepath.Join(dir, "exe")
+	if err := os.WriteFile(exe, []byte("x"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if !IsExecutable(exe, nil) {
+		t.Errorf("expected %q to be executable", exe)
+	}
+
+	plain := filepath.Join(dir, "plain")
+	if err := os.WriteFile(plain, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if IsExecutable(plain, nil) {
+		t.Errorf("expected %q not to be executable", plain)
+	}
+
+	missing := filepath.Join(dir, "missing")
+	if IsExecutable(missing, nil) {
+		t.Errorf("expected a missing file not to be executable")
+	}
+}
+
+func TestIsExecutableUsesDirEntryModeOnEACCES(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "exe")
+	if err := os.WriteFile(exe, []byte("x"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// mode_is_executable is exercised directly since forcing access(2)
+	// itself to return EACCES for a file our own process can otherwise
+	// reach requires privileges (or filesystem features) this sandbox may
+	// not have.
+	if !mode_is_executable(exe, nil) {
+		t.Errorf("expected mode_is_executable(%q) to report executable", exe)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mode_is_executable(exe, entries[0]) {
+		t.Errorf("expected mode_is_executable with a DirEntry to report executable")
+	}
+}
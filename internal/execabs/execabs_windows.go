@@ -0,0 +1,41 @@
+// License: GPLv3 Copyright: 2022, Kovid Goyal, <kovid at kovidgoyal.net>
+
+//go:build windows
+
+package execabs
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// default_pathext is the PATHEXT value cmd.exe itself falls back to when
+// the environment variable is unset.
+const default_pathext = ".COM;.EXE;.BAT;.CMD"
+
+// IsExecutable reports whether path's extension is one of PATHEXT's, the
+// same way os/exec.LookPath decides whether a file is runnable, since the
+// execute permission bit unix relies on is meaningless on Windows.
+// PATHEXT is read from the environment, so user overrides of the default
+// list are honoured.
+func IsExecutable(path string, d fs.DirEntry) bool {
+	if d != nil && d.IsDir() {
+		return false
+	}
+	ext := filepath.Ext(path)
+	if ext == "" {
+		return false
+	}
+	pathext := os.Getenv("PATHEXT")
+	if pathext == "" {
+		pathext = default_pathext
+	}
+	for _, e := range strings.Split(pathext, ";") {
+		if e != "" && strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+// License: GPLv3 Copyright: 2022, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package completion
+
+import "kitty/tools/completion/pathindex"
+
+// complete_executables_in_path returns the names of every executable in
+// $PATH whose name starts with prefix, deduplicated and sorted, with
+// earlier PATH entries shadowing later ones the same way exec lookup does.
+// The underlying PATH directories are cached by pathindex so this is cheap
+// to call on every keystroke of tab completion.
+func complete_executables_in_path(prefix string) []string {
+	return pathindex.Lookup(prefix)
+}
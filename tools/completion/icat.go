@@ -0,0 +1,22 @@
+// License: GPLv3 Copyright: 2022, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package completion
+
+import "os"
+
+// complete_icat completes the argument to `kitty icat`, which previews an
+// image in the terminal, so only files that are actually images are
+// offered.
+func complete_icat(completions *Completions, word string, arg_num int) {
+	mg := completions.add_match_group("Images")
+	mg.IsFiles = true
+	mg.MimeTypes = []string{"image/*"}
+
+	cwd, _ := os.Getwd()
+	CompleteFiles(word, cwd, completions.max_symlink_depth(), func(fe *FileEntry) bool {
+		if completions.file_entry_matches(mg, fe) {
+			mg.add_match(fe.CompletionCandidate)
+		}
+		return true
+	})
+}
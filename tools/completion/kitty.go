@@ -4,16 +4,17 @@ package completion
 
 import (
 	"fmt"
-	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"golang.org/x/sys/unix"
+	"kitty/internal/execabs"
 )
 
 var _ = fmt.Print
 
+const executables_file_matcher = "kitty.executables"
+
 func complete_kitty(completions *Completions, word string, arg_num int) {
 	exes := complete_executables_in_path(word)
 	if len(exes) > 0 {
@@ -26,22 +27,44 @@ func complete_kitty(completions *Completions, word string, arg_num int) {
 	if len(word) > 0 && (filepath.IsAbs(word) || strings.HasPrefix(word, "./")) {
 		mg := completions.add_match_group("Executables")
 		mg.IsFiles = true
+		mg.FileMatcher = executables_file_matcher
+		max_depth := completions.max_symlink_depth()
+		completions.RegisterFileMatcher(executables_file_matcher, func(fe *FileEntry) bool {
+			return is_executable_or_has_executables(fe, max_depth)
+		})
 
-		complete_files(word, func(q, abspath string, d fs.DirEntry) error {
-			if d.IsDir() {
-				// only allow directories that have sub-dirs or executable files in them
-				entries, err := os.ReadDir(abspath)
-				if err == nil {
-					for _, x := range entries {
-						if x.IsDir() || unix.Access(filepath.Join(abspath, x.Name()), unix.X_OK) == nil {
-							mg.add_match(q)
-						}
-					}
-				}
-			} else if unix.Access(abspath, unix.X_OK) == nil {
-				mg.add_match(q)
+		cwd, _ := os.Getwd()
+		CompleteFiles(word, cwd, max_depth, func(fe *FileEntry) bool {
+			// file_entry_matches never filters directories, so dead-end
+			// directories are weeded out here.
+			if completions.file_entry_matches(mg, fe) && (!fe.IsDir || is_executable_or_has_executables(fe, max_depth)) {
+				mg.add_match(fe.CompletionCandidate)
 			}
-			return nil
+			return true
 		})
 	}
 }
+
+// is_executable_or_has_executables only allows directories that have
+// sub-dirs or executable files in them, so tab completion does not lead
+// into dead ends. Both the entry itself and, for directories, its
+// children, are classified after resolving symlinks, so a directory of
+// nothing but symlinks to executables, or a symlink directly to an
+// executable, are matched just as readily as the plain-file case.
+func is_executable_or_has_executables(fe *FileEntry, max_symlink_depth int) bool {
+	if !fe.IsDir {
+		return execabs.IsExecutable(fe.Abspath, nil)
+	}
+	if fe.IsEmptyDir {
+		return false
+	}
+	has_match := false
+	for_each_dir_entry(fe.Abspath, max_symlink_depth, func(sub *FileEntry) bool {
+		if sub.IsDir || execabs.IsExecutable(sub.Abspath, nil) {
+			has_match = true
+			return false // stop at the first match, no need to scan the rest of the directory
+		}
+		return true
+	})
+	return has_match
+}
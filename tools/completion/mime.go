@@ -0,0 +1,78 @@
+// License: GPLv3 Copyright: 2022, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package completion
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sniff_len mirrors the 512 byte header http.DetectContentType itself
+// requires, so there is no point reading more than this from extensionless
+// files.
+const sniff_len = 512
+
+// mime_type_cache memoizes detected MIME types per absolute path for the
+// lifetime of a single completion invocation, so that a path considered by
+// more than one match group, or listed more than once while walking a
+// directory tree, is only read from disk once.
+type mime_type_cache struct {
+	cache map[string]string
+}
+
+func new_mime_type_cache() *mime_type_cache {
+	return &mime_type_cache{cache: make(map[string]string)}
+}
+
+func (c *mime_type_cache) detect(abspath string) string {
+	if t, ok := c.cache[abspath]; ok {
+		return t
+	}
+	t := detect_mime_type(abspath)
+	c.cache[abspath] = t
+	return t
+}
+
+// detect_mime_type first takes the cheap path of mapping the file's
+// extension to a MIME type and only falls back to sniffing the file's
+// header, the way http.DetectContentType does, when the extension is
+// unknown or absent.
+func detect_mime_type(abspath string) string {
+	if t := mime.TypeByExtension(filepath.Ext(abspath)); t != "" {
+		return t
+	}
+	f, err := os.Open(abspath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	buf := make([]byte, sniff_len)
+	n, err := f.Read(buf)
+	if n == 0 && err != nil {
+		return ""
+	}
+	return http.DetectContentType(buf[:n])
+}
+
+// mime_type_matches reports whether detected satisfies any of patterns,
+// each of which is either a full MIME type ("application/pdf") or a
+// type/* wildcard ("image/*").
+func mime_type_matches(patterns []string, detected string) bool {
+	if detected == "" {
+		return false
+	}
+	main_type, _, _ := strings.Cut(detected, ";")
+	main_type = strings.TrimSpace(main_type)
+	for _, p := range patterns {
+		if p == detected || p == main_type {
+			return true
+		}
+		if strings.HasSuffix(p, "/*") && strings.TrimSuffix(p, "/*") == strings.SplitN(main_type, "/", 2)[0] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+// License: GPLv3 Copyright: 2022, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package completion
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMimeTypeMatches(t *testing.T) {
+	cases := []struct {
+		patterns []string
+		detected string
+		want     bool
+	}{
+		{[]string{"image/*"}, "image/png", true},
+		{[]string{"image/*"}, "image/jpeg; charset=binary", true},
+		{[]string{"application/pdf"}, "application/pdf", true},
+		{[]string{"application/pdf"}, "application/json", false},
+		{[]string{"image/*"}, "application/pdf", false},
+		{[]string{"image/*", "application/pdf"}, "application/pdf", true},
+		{[]string{"image/*"}, "", false},
+		{nil, "image/png", false},
+	}
+	for _, c := range cases {
+		if got := mime_type_matches(c.patterns, c.detected); got != c.want {
+			t.Errorf("mime_type_matches(%v, %q) = %v, want %v", c.patterns, c.detected, got, c.want)
+		}
+	}
+}
+
+func TestCompleteIcatOnlyMatchesImages(t *testing.T) {
+	dir := t.TempDir()
+	image := dir + "/photo.png"
+	text := dir + "/notes.txt"
+	for _, p := range []string{image, text} {
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	completions := &Completions{}
+	complete_icat(completions, dir+"/", 0)
+	if len(completions.Groups) != 1 {
+		t.Fatalf("expected one match group, got %d", len(completions.Groups))
+	}
+	mg := completions.Groups[0]
+	found_image, found_text := false, false
+	for _, m := range mg.Matches {
+		if strings.HasSuffix(m.Word, "photo.png") {
+			found_image = true
+		}
+		if strings.HasSuffix(m.Word, "notes.txt") {
+			found_text = true
+		}
+	}
+	if !found_image {
+		t.Fatalf("expected photo.png to be offered, matches: %v", mg.Matches)
+	}
+	if found_text {
+		t.Fatalf("did not expect notes.txt to be offered, matches: %v", mg.Matches)
+	}
+}
@@ -0,0 +1,30 @@
+// License: GPLv3 Copyright: 2022, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package completion
+
+import "os"
+
+// default_open_mime_types is what complete_open restricts matches to when
+// Completions.OpenMimeTypes is left unset.
+var default_open_mime_types = []string{"image/*", "application/pdf"}
+
+// complete_open completes the argument to `kitty +open`, which hands a
+// file to whatever program the user or kitty.conf has configured for it,
+// restricting matches to a configurable set of MIME types rather than
+// offering every file on disk.
+func complete_open(completions *Completions, word string, arg_num int) {
+	mg := completions.add_match_group("Files")
+	mg.IsFiles = true
+	mg.MimeTypes = completions.OpenMimeTypes
+	if len(mg.MimeTypes) == 0 {
+		mg.MimeTypes = default_open_mime_types
+	}
+
+	cwd, _ := os.Getwd()
+	CompleteFiles(word, cwd, completions.max_symlink_depth(), func(fe *FileEntry) bool {
+		if completions.file_entry_matches(mg, fe) {
+			mg.add_match(fe.CompletionCandidate)
+		}
+		return true
+	})
+}
@@ -0,0 +1,308 @@
+// License: GPLv3 Copyright: 2022, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package completion
+
+import (
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// Match is a single completion candidate together with the optional
+// human readable text shown next to it.
+type Match struct {
+	Word        string
+	Description string
+}
+
+// MatchGroup is a titled set of Matches rendered together by the shell's
+// completion widget.
+type MatchGroup struct {
+	Title           string
+	Matches         []*Match
+	IsFiles         bool
+	NoTrailingSpace bool
+
+	// MimeTypes restricts file Matches added while this group is being
+	// populated to files whose detected MIME type matches one of these
+	// patterns, e.g. "image/*" or "application/pdf". An empty slice means
+	// no filtering is performed. Only consulted for plain files; entries
+	// that are directories are never filtered out since completion needs
+	// to be able to descend into them.
+	MimeTypes []string
+
+	// FileMatcher, if non-empty, names a predicate previously registered
+	// with Completions.RegisterFileMatcher that every plain-file
+	// FileEntry must satisfy to be offered as a match.
+	FileMatcher string
+}
+
+func (mg *MatchGroup) add_match(word string, description ...string) {
+	m := &Match{Word: word}
+	if len(description) > 0 {
+		m.Description = description[0]
+	}
+	mg.Matches = append(mg.Matches, m)
+}
+
+// default_max_symlink_depth bounds how many symlink hops CompleteFiles will
+// follow to classify an entry when MaxSymlinkDepth is left at its zero
+// value.
+const default_max_symlink_depth = 8
+
+// Completions accumulates the MatchGroups produced while answering a single
+// completion request.
+type Completions struct {
+	Groups []*MatchGroup
+
+	// MaxSymlinkDepth bounds how many symlink hops CompleteFiles follows
+	// to classify an entry as a directory or a file. Zero means
+	// default_max_symlink_depth.
+	MaxSymlinkDepth int
+
+	// OpenMimeTypes restricts the files complete_open offers to those
+	// matching one of these MIME type patterns, e.g. "image/*" or
+	// "application/pdf". Empty means default_open_mime_types.
+	OpenMimeTypes []string
+
+	mime_cache    *mime_type_cache
+	file_matchers map[string]func(*FileEntry) bool
+}
+
+func (c *Completions) max_symlink_depth() int {
+	if c.MaxSymlinkDepth > 0 {
+		return c.MaxSymlinkDepth
+	}
+	return default_max_symlink_depth
+}
+
+func (c *Completions) add_match_group(title string) *MatchGroup {
+	for _, g := range c.Groups {
+		if g.Title == title {
+			return g
+		}
+	}
+	ans := &MatchGroup{Title: title}
+	c.Groups = append(c.Groups, ans)
+	return ans
+}
+
+// RegisterFileMatcher makes pred available to any MatchGroup whose
+// FileMatcher field is set to name, letting a subcommand's completer filter
+// files (by extension, content, whatever pred wants) without reimplementing
+// CompleteFiles' path parsing. Registering under a name that already exists
+// replaces the previous predicate.
+func (c *Completions) RegisterFileMatcher(name string, pred func(*FileEntry) bool) {
+	if c.file_matchers == nil {
+		c.file_matchers = make(map[string]func(*FileEntry) bool)
+	}
+	c.file_matchers[name] = pred
+}
+
+// mime_type returns the, possibly cached, detected MIME type of abspath.
+func (c *Completions) mime_type(abspath string) string {
+	if c.mime_cache == nil {
+		c.mime_cache = new_mime_type_cache()
+	}
+	return c.mime_cache.detect(abspath)
+}
+
+// file_entry_matches reports whether fe should be offered as a match for
+// mg, applying mg.MimeTypes and mg.FileMatcher, if either is set. Neither
+// filter is applied to directories, since completion needs to be able to
+// descend into them regardless of their own type.
+func (c *Completions) file_entry_matches(mg *MatchGroup, fe *FileEntry) bool {
+	if mg == nil || fe.IsDir {
+		return true
+	}
+	if len(mg.MimeTypes) > 0 && !mime_type_matches(mg.MimeTypes, c.mime_type(fe.Abspath)) {
+		return false
+	}
+	if mg.FileMatcher != "" {
+		if pred, ok := c.file_matchers[mg.FileMatcher]; ok && !pred(fe) {
+			return false
+		}
+	}
+	return true
+}
+
+// FileEntry describes a single filesystem entry produced by CompleteFiles.
+type FileEntry struct {
+	Name                string      // the entry's own base name
+	CompletionCandidate string      // text that should replace the word being completed
+	Abspath             string      // absolute path of the entry itself; symlinks in it are not resolved
+	Mode                fs.FileMode // type bits (fs.DirEntry.Type) of the entry itself, not its symlink target; no permission bits
+	IsDir               bool
+	IsSymlink           bool
+	IsEmptyDir          bool
+}
+
+// CompleteFiles calls cb once for every filesystem entry whose name matches
+// the last path component of prefix, stopping as soon as cb returns false.
+// prefix may be empty, start with ~ or ~user, be relative (resolved
+// against cwd), start with ./, or be absolute; a trailing path separator
+// on prefix means "list the contents of this directory" rather than
+// "match siblings of this name".
+//
+// A symlink entry is classified by what it points to, resolved up to
+// max_symlink_depth hops, with cycle detection, the same way
+// filepath.EvalSymlinks resolves a chain; a symlink that cannot be
+// resolved within that bound (a cycle, a dangling target, or too long a
+// chain) is classified as neither a directory nor an empty directory,
+// so it is left for the caller's own predicates to accept or reject.
+func CompleteFiles(prefix, cwd string, max_symlink_depth int, cb func(*FileEntry) bool) error {
+	if max_symlink_depth <= 0 {
+		max_symlink_depth = default_max_symlink_depth
+	}
+	dirname, base := filepath.Split(prefix)
+	scan_dir := dirname
+	switch {
+	case scan_dir == "":
+		scan_dir = cwd
+	case strings.HasPrefix(scan_dir, "~"):
+		if resolved := tilde_dir(strings.TrimPrefix(scan_dir, "~")); resolved != "" {
+			scan_dir = resolved
+		} else {
+			scan_dir = filepath.Join(cwd, scan_dir)
+		}
+	case !filepath.IsAbs(scan_dir):
+		scan_dir = filepath.Join(cwd, scan_dir)
+	}
+	if scan_dir == "" {
+		scan_dir = "."
+	}
+
+	entries, err := os.ReadDir(scan_dir)
+	if err != nil {
+		return nil
+	}
+
+	for _, d := range entries {
+		name := d.Name()
+		if base == "" {
+			if strings.HasPrefix(name, ".") {
+				continue
+			}
+		} else if !strings.HasPrefix(name, base) {
+			continue
+		}
+		if !cb(make_file_entry(dirname, scan_dir, d, max_symlink_depth)) {
+			break
+		}
+	}
+	return nil
+}
+
+// for_each_dir_entry calls cb once for every entry of dir, dotfiles
+// included, building each FileEntry the same way CompleteFiles does,
+// stopping as soon as cb returns false. It exists for internal callers,
+// such as the directory-descent check in complete_kitty's executable
+// matcher, that need to know what a directory contains rather than offer
+// its contents as completions, and so must not apply CompleteFiles'
+// tab-completion convention of hiding dotfiles.
+func for_each_dir_entry(dir string, max_symlink_depth int, cb func(*FileEntry) bool) error {
+	if max_symlink_depth <= 0 {
+		max_symlink_depth = default_max_symlink_depth
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, d := range entries {
+		if !cb(make_file_entry("", dir, d, max_symlink_depth)) {
+			break
+		}
+	}
+	return nil
+}
+
+// make_file_entry builds the FileEntry for d, an entry of scan_dir, where
+// dirname is the, possibly empty, directory portion of the prefix the
+// caller is completing (used only to build CompletionCandidate).
+func make_file_entry(dirname, scan_dir string, d fs.DirEntry, max_symlink_depth int) *FileEntry {
+	name := d.Name()
+	abspath := filepath.Join(scan_dir, name)
+	if a, err := filepath.Abs(abspath); err == nil {
+		abspath = a
+	}
+	mode := d.Type()
+	is_symlink := mode&fs.ModeSymlink != 0
+	is_dir := d.IsDir()
+	if is_symlink {
+		if _, target_is_dir, ok := resolve_symlink(abspath, max_symlink_depth); ok {
+			is_dir = target_is_dir
+		}
+	}
+	candidate := dirname + name
+	if is_dir {
+		candidate += string(filepath.Separator)
+	}
+	return &FileEntry{
+		Name:                name,
+		CompletionCandidate: candidate,
+		Abspath:             abspath,
+		Mode:                mode,
+		IsDir:               is_dir,
+		IsSymlink:           is_symlink,
+		IsEmptyDir:          is_dir && dir_is_empty(abspath),
+	}
+}
+
+// tilde_dir resolves rest, the part of a path following a ~, to an
+// absolute directory. rest is empty for a bare ~ (the current user's home
+// directory), or of the form "user" or "user/sub/dirs" for ~user and
+// ~user/sub/dirs. It returns "" if the relevant home directory could not
+// be determined (e.g. an unknown username), leaving the caller to fall
+// back to treating the ~ as a literal directory name.
+func tilde_dir(rest string) string {
+	username, tail, _ := strings.Cut(rest, "/")
+	var home string
+	if username == "" {
+		home, _ = os.UserHomeDir()
+	} else if u, err := user.Lookup(username); err == nil {
+		home = u.HomeDir
+	}
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, tail)
+}
+
+func dir_is_empty(abspath string) bool {
+	entries, err := os.ReadDir(abspath)
+	return err == nil && len(entries) == 0
+}
+
+// resolve_symlink follows path through at most max_depth symlink hops,
+// returning the final, non-symlink target's own path and whether it is a
+// directory. ok is false if the chain is too long, dangling, or cyclic,
+// the same cases filepath.EvalSymlinks itself refuses to resolve.
+func resolve_symlink(path string, max_depth int) (resolved string, is_dir bool, ok bool) {
+	seen := make(map[string]bool, max_depth)
+	for depth := 0; depth <= max_depth; depth++ {
+		if seen[path] {
+			return "", false, false
+		}
+		seen[path] = true
+
+		fi, err := os.Lstat(path)
+		if err != nil {
+			return "", false, false
+		}
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			return path, fi.IsDir(), true
+		}
+
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", false, false
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = filepath.Clean(target)
+	}
+	return "", false, false
+}
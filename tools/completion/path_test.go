@@ -0,0 +1,141 @@
+// License: GPLv3 Copyright: 2022, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package completion
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompleteFilesStopsWhenCallbackReturnsFalse(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	seen := 0
+	CompleteFiles("", dir, 0, func(fe *FileEntry) bool {
+		seen++
+		return false
+	})
+	if seen != 1 {
+		t.Fatalf("expected iteration to stop after the first entry, cb ran %d times", seen)
+	}
+}
+
+func TestCompleteFilesTildeExpansion(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("no home directory available: %v", err)
+	}
+	base := filepath.Base(t.TempDir())
+	dir := filepath.Join(home, base)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Skipf("cannot create a directory under home: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(dir) })
+
+	seen := false
+	CompleteFiles("~/"+base, "/unrelated/cwd", 0, func(fe *FileEntry) bool {
+		if fe.Name == base {
+			seen = true
+		}
+		return true
+	})
+	if !seen {
+		t.Fatalf("expected ~/%s to resolve under the home directory", base)
+	}
+}
+
+func TestCompleteFilesTildeUserExpansion(t *testing.T) {
+	me, err := user.Current()
+	if err != nil || me.Username == "" {
+		t.Skipf("cannot determine current user: %v", err)
+	}
+	if _, err := user.Lookup(me.Username); err != nil {
+		t.Skipf("user.Lookup unavailable in this environment: %v", err)
+	}
+
+	base := filepath.Base(t.TempDir())
+	dir := filepath.Join(me.HomeDir, base)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Skipf("cannot create a directory under home: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(dir) })
+
+	seen := false
+	CompleteFiles("~"+me.Username+"/"+base, "/unrelated/cwd", 0, func(fe *FileEntry) bool {
+		if fe.Name == base {
+			seen = true
+		}
+		return true
+	})
+	if !seen {
+		t.Fatalf("expected ~%s/%s to resolve under %s", me.Username, base, me.HomeDir)
+	}
+}
+
+func TestResolveSymlinkFollowsChainToTarget(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	if err := os.Mkdir(target, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	link1 := filepath.Join(dir, "link1")
+	link2 := filepath.Join(dir, "link2")
+	if err := os.Symlink(target, link1); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := os.Symlink(link1, link2); err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, is_dir, ok := resolve_symlink(link2, default_max_symlink_depth)
+	if !ok || !is_dir || resolved != target {
+		t.Fatalf("resolve_symlink(link2) = %q, %v, %v; want %q, true, true", resolved, is_dir, ok, target)
+	}
+}
+
+func TestResolveSymlinkDetectsCycle(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	if err := os.Symlink(b, a); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := os.Symlink(a, b); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, ok := resolve_symlink(a, default_max_symlink_depth); ok {
+		t.Fatalf("expected a symlink cycle to fail to resolve")
+	}
+}
+
+func TestResolveSymlinkRespectsMaxDepth(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	if err := os.Mkdir(target, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	prev := target
+	for i := 0; i < 5; i++ {
+		next := filepath.Join(dir, "link", string(rune('a'+i)))
+		_ = os.MkdirAll(filepath.Dir(next), 0o755)
+		if err := os.Symlink(prev, next); err != nil {
+			t.Skipf("symlinks unavailable: %v", err)
+		}
+		prev = next
+	}
+
+	if _, _, ok := resolve_symlink(prev, 2); ok {
+		t.Fatalf("expected a 5 hop chain to exceed a max depth of 2")
+	}
+	if _, is_dir, ok := resolve_symlink(prev, default_max_symlink_depth); !ok || !is_dir {
+		t.Fatalf("expected the same chain to resolve within the default depth")
+	}
+}
@@ -0,0 +1,109 @@
+// License: GPLv3 Copyright: 2022, Kovid Goyal, <kovid at kovidgoyal.net>
+
+// Package pathindex maintains a process-wide, cached index of the
+// executables available in every directory on $PATH, so that repeated tab
+// completion requests (one per keystroke) do not have to re-read every
+// PATH directory from scratch.
+package pathindex
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kitty/internal/execabs"
+)
+
+type dir_cache_entry struct {
+	mtime time.Time
+	names []string
+}
+
+var (
+	mu    sync.Mutex
+	cache = make(map[string]*dir_cache_entry)
+)
+
+// Lookup returns the names of every executable in $PATH whose name starts
+// with prefix, deduplicated and sorted, with earlier PATH entries
+// shadowing later ones, the same way exec lookup does. Each PATH directory
+// is only re-read when its mtime has changed since the last Lookup or,
+// where a watcher is available, when the watcher has invalidated it.
+func Lookup(prefix string) []string {
+	seen := make(map[string]bool)
+	ans := make([]string, 0, 32)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		for _, name := range entries_for(dir) {
+			if seen[name] || !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			seen[name] = true
+			ans = append(ans, name)
+		}
+	}
+	sort.Strings(ans)
+	return ans
+}
+
+// entries_for returns the cached executable names for dir, re-scanning it
+// only if it is not yet cached or its mtime has moved since it was.
+func entries_for(dir string) []string {
+	st, err := os.Stat(dir)
+	if err != nil {
+		invalidate(dir)
+		return nil
+	}
+	mtime := st.ModTime()
+
+	mu.Lock()
+	e, ok := cache[dir]
+	mu.Unlock()
+	if ok && e.mtime.Equal(mtime) {
+		return e.names
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, d := range entries {
+		path := filepath.Join(dir, d.Name())
+		if d.IsDir() {
+			continue
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			// d.IsDir() above only reflects the symlink's own type bit, not
+			// its target's, so a symlink to a directory falls through to
+			// here; access(2) below would happily pass X_OK for it since
+			// that just means "searchable", not "runnable".
+			if target, err := os.Stat(path); err == nil && target.IsDir() {
+				continue
+			}
+		}
+		if execabs.IsExecutable(path, d) {
+			names = append(names, d.Name())
+		}
+	}
+
+	mu.Lock()
+	cache[dir] = &dir_cache_entry{mtime: mtime, names: names}
+	mu.Unlock()
+	watch(dir)
+	return names
+}
+
+// invalidate drops dir from the cache, forcing the next Lookup to re-scan
+// it from scratch.
+func invalidate(dir string) {
+	mu.Lock()
+	delete(cache, dir)
+	mu.Unlock()
+}
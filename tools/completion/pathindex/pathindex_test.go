@@ -0,0 +1,86 @@
+// License: GPLv3 Copyright: 2022, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package pathindex
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func make_executable(t *testing.T, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEntriesForCachesUntilMtimeChanges(t *testing.T) {
+	dir := t.TempDir()
+	make_executable(t, filepath.Join(dir, "foo"))
+
+	names := entries_for(dir)
+	if len(names) != 1 || names[0] != "foo" {
+		t.Fatalf("expected [foo], got %v", names)
+	}
+
+	// Adding a file without the directory's mtime moving forward (same
+	// resolution as before) must not be picked up: that is the whole
+	// point of the cache.
+	make_executable(t, filepath.Join(dir, "bar"))
+	st, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	cache[dir] = &dir_cache_entry{mtime: st.ModTime(), names: names}
+	mu.Unlock()
+	if names := entries_for(dir); len(names) != 1 {
+		t.Fatalf("expected cached stale result [foo], got %v", names)
+	}
+
+	invalidate(dir)
+	names = entries_for(dir)
+	if len(names) != 2 {
+		t.Fatalf("expected [bar foo] after invalidate, got %v", names)
+	}
+}
+
+func TestEntriesForSkipsDirectoriesAndSymlinksToDirectories(t *testing.T) {
+	dir := t.TempDir()
+	make_executable(t, filepath.Join(dir, "real_exe"))
+	subdir := filepath.Join(dir, "subdir")
+	if err := os.Mkdir(subdir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(subdir, filepath.Join(dir, "link_to_dir")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	names := entries_for(dir)
+	for _, n := range names {
+		if n == "subdir" || n == "link_to_dir" {
+			t.Fatalf("directory (or symlink to one) %q incorrectly treated as executable, got %v", n, names)
+		}
+	}
+	found := false
+	for _, n := range names {
+		found = found || n == "real_exe"
+	}
+	if !found {
+		t.Fatalf("expected real_exe in %v", names)
+	}
+}
+
+func TestEntriesForMissingDirInvalidatesCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "does-not-exist")
+	if names := entries_for(dir); names != nil {
+		t.Fatalf("expected nil for missing dir, got %v", names)
+	}
+	mu.Lock()
+	_, ok := cache[dir]
+	mu.Unlock()
+	if ok {
+		t.Fatalf("missing dir must not be cached")
+	}
+}
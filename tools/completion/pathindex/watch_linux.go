@@ -0,0 +1,91 @@
+// License: GPLv3 Copyright: 2022, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package pathindex
+
+import (
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// watch_mask covers the filesystem events that can change which
+// executables a directory contains.
+const watch_mask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_ATTRIB
+
+var (
+	watch_once sync.Once
+	watch_mu   sync.Mutex
+	watched    = make(map[string]bool)
+	wd_to_dir  = make(map[int32]string)
+	inotify_fd = -1
+)
+
+// watch arranges for dir to be invalidated the next time one of its
+// entries is created, removed, renamed or has its permissions changed, so
+// that a long-running completion daemon never has to rely solely on
+// mtime polling to notice PATH changes. Failures are silent: Lookup then
+// relies on its mtime check alone, which notices entries being added,
+// removed or renamed, but not a permission change to an existing entry.
+func watch(dir string) {
+	watch_mu.Lock()
+	already := watched[dir]
+	watch_mu.Unlock()
+	if already {
+		return
+	}
+
+	watch_once.Do(start_watch_loop)
+
+	watch_mu.Lock()
+	fd := inotify_fd
+	watch_mu.Unlock()
+	if fd < 0 {
+		return
+	}
+
+	wd, err := unix.InotifyAddWatch(fd, dir, watch_mask)
+	if err != nil {
+		// Leave watched[dir] unset so a later watch(dir) call, made the next
+		// time dir is re-scanned, retries instead of falling back to
+		// mtime-polling forever.
+		return
+	}
+	watch_mu.Lock()
+	watched[dir] = true
+	wd_to_dir[int32(wd)] = dir
+	watch_mu.Unlock()
+}
+
+func start_watch_loop() {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return
+	}
+	watch_mu.Lock()
+	inotify_fd = fd
+	watch_mu.Unlock()
+
+	go read_events(fd)
+}
+
+func read_events(fd int) {
+	buf := make([]byte, 64*unix.SizeofInotifyEvent)
+	for {
+		n, err := unix.Read(fd, buf)
+		if err != nil || n <= 0 {
+			return
+		}
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			offset += unix.SizeofInotifyEvent + int(raw.Len)
+
+			watch_mu.Lock()
+			dir := wd_to_dir[raw.Wd]
+			watch_mu.Unlock()
+			if dir != "" {
+				invalidate(dir)
+			}
+		}
+	}
+}
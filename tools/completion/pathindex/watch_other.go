@@ -0,0 +1,10 @@
+// License: GPLv3 Copyright: 2022, Kovid Goyal, <kovid at kovidgoyal.net>
+
+//go:build !linux
+
+package pathindex
+
+// watch is a no-op on platforms without inotify; entries_for's mtime check
+// alone notices entries being added, removed or renamed, but not a
+// permission change to an existing entry while the cache is warm.
+func watch(dir string) {}